@@ -0,0 +1,125 @@
+package loadspec
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// grokRefRe matches %{NAME} and %{NAME:capture} references inside a pattern definition.
+var grokRefRe = regexp.MustCompile(`%\{([A-Za-z0-9_]+)(?::([A-Za-z0-9_]+))?\}`)
+
+// maxGrokExpansionDepth bounds the recursive substitution below, in case a pattern
+// library ends up with a reference cycle.
+const maxGrokExpansionDepth = 32
+
+// builtinGrokPatterns are the reusable fragments plus one named top-level pattern per
+// Elasticsearch major version line. The bracketed slowlog layout has stayed close to
+// identical since 1.x, but we keep them as separate names so a --patterns-file can
+// override a single version without touching the others.
+var builtinGrokPatterns = map[string]string{
+	"TIMESTAMP_ISO8601": `[0-9]{4}-[0-9]{2}-[0-9]{2}[ T][0-9]{2}:[0-9]{2}:[0-9]{2}(?:[.,][0-9]+)?`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+
+	"ES_SLOWLOG_1X": `\[%{TIMESTAMP_ISO8601:ts}\].?\[.*\].?\[%{DATA:log_type}\].?\[%{DATA:host}\].?\[%{DATA:index}\].?\[.*\].*types\[%{DATA:types}\].*search_type\[%{DATA:search_type}\].*source\[%{GREEDYDATA:source}\], extra_source`,
+	"ES_SLOWLOG_2X": `\[%{TIMESTAMP_ISO8601:ts}\].?\[.*\].?\[%{DATA:log_type}\].?\[%{DATA:host}\].?\[%{DATA:index}\].?\[.*\].*types\[%{DATA:types}\].*search_type\[%{DATA:search_type}\].*source\[%{GREEDYDATA:source}\], extra_source`,
+	"ES_SLOWLOG_5X": `\[%{TIMESTAMP_ISO8601:ts}\]\[%{DATA:level}\]\s*\[%{DATA:log_type}\]\s*\[%{DATA:host}\]\s*\[%{DATA:index}\]\[.*\].*types\[%{DATA:types}\].*search_type\[%{DATA:search_type}\].*source\[%{GREEDYDATA:source}\], extra_source`,
+	"ES_SLOWLOG_6X": `\[%{TIMESTAMP_ISO8601:ts}\]\[%{DATA:level}\]\s*\[%{DATA:log_type}\]\s*\[%{DATA:host}\]\s*\[%{DATA:index}\]\[.*\].*types\[%{DATA:types}\].*search_type\[%{DATA:search_type}\].*source\[%{GREEDYDATA:source}\], extra_source`,
+	"ES_SLOWLOG_7X": `\[%{TIMESTAMP_ISO8601:ts}\]\[%{DATA:log_type}\]\s*\[%{DATA:host}\]\s*\[%{DATA:index}\]\[.*\].*types\[%{DATA:types}\].*search_type\[%{DATA:search_type}\].*source\[%{GREEDYDATA:source}\], extra_source`,
+	"ES_SLOWLOG_8X": `\[%{TIMESTAMP_ISO8601:ts}\]\[%{DATA:log_type}\]\s*\[%{DATA:host}\]\s*\[%{DATA:index}\]\[.*\].*types\[%{DATA:types}\].*search_type\[%{DATA:search_type}\].*source\[%{GREEDYDATA:source}\], extra_source`,
+}
+
+// defaultGrokPattern keeps today's behavior as the default --pattern value.
+const defaultGrokPattern = "ES_SLOWLOG_6X"
+
+// requiredCaptureNames are the loadspec fields parseSlowlogCmd needs out of whichever
+// pattern ends up compiled, regardless of where it came from.
+var requiredCaptureNames = []string{logTypeField, hostField, timestampField, indexField, typesField, searchTypeField, sourceField}
+
+// loadPatternsFile reads additional or overriding grok fragment definitions from a
+// simple "NAME pattern" per-line file and merges them on top of builtinGrokPatterns.
+func loadPatternsFile(path string, into map[string]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("patterns file %s: malformed line %q, expected \"NAME pattern\"", path, line)
+		}
+		into[parts[0]] = strings.TrimSpace(parts[1])
+	}
+	return scanner.Err()
+}
+
+// expandGrokPattern resolves %{NAME} and %{NAME:capture} references against defs,
+// recursively, until the result is a plain Go regexp. name may either be a key in
+// defs or a literal grok expression (e.g. one passed directly via --pattern).
+func expandGrokPattern(defs map[string]string, name string) (string, error) {
+	pattern, ok := defs[name]
+	if !ok {
+		pattern = name
+	}
+	for depth := 0; strings.Contains(pattern, "%{"); depth++ {
+		if depth >= maxGrokExpansionDepth {
+			return "", fmt.Errorf("grok pattern %q did not converge after %d expansions, check for a reference cycle", name, maxGrokExpansionDepth)
+		}
+		var expandErr error
+		pattern = grokRefRe.ReplaceAllStringFunc(pattern, func(ref string) string {
+			m := grokRefRe.FindStringSubmatch(ref)
+			refName, capture := m[1], m[2]
+			sub, ok := defs[refName]
+			if !ok {
+				expandErr = fmt.Errorf("grok pattern %q references unknown pattern %%{%s}", name, refName)
+				return ref
+			}
+			if capture != "" {
+				return fmt.Sprintf("(?P<%s>%s)", capture, sub)
+			}
+			return sub
+		})
+		if expandErr != nil {
+			return "", expandErr
+		}
+	}
+	return pattern, nil
+}
+
+// compileGrokPattern expands name into a regexp and checks that every capture group
+// parseSlowlogCmd relies on made it through the expansion.
+func compileGrokPattern(defs map[string]string, name string) (*regexp.Regexp, error) {
+	expanded, err := expandGrokPattern(defs, name)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("pattern %q expanded to invalid regexp: %w", name, err)
+	}
+
+	have := make(map[string]bool, len(re.SubexpNames()))
+	for _, n := range re.SubexpNames() {
+		have[n] = true
+	}
+	var missing []string
+	for _, required := range requiredCaptureNames {
+		if !have[required] {
+			missing = append(missing, required)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("pattern %q is missing required capture group(s): %s", name, strings.Join(missing, ", "))
+	}
+	return re, nil
+}
@@ -0,0 +1,146 @@
+package loadspec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandGrokPattern(t *testing.T) {
+	defs := map[string]string{
+		"DIGITS": `[0-9]+`,
+		"ID":     `id-%{DIGITS:id}`,
+	}
+	got, err := expandGrokPattern(defs, "ID")
+	if err != nil {
+		t.Fatalf("expandGrokPattern: %v", err)
+	}
+	want := `id-(?P<id>[0-9]+)`
+	if got != want {
+		t.Fatalf("expandGrokPattern = %q, want %q", got, want)
+	}
+}
+
+func TestExpandGrokPatternLiteral(t *testing.T) {
+	defs := map[string]string{"DIGITS": `[0-9]+`}
+	got, err := expandGrokPattern(defs, `%{DIGITS:n}-suffix`)
+	if err != nil {
+		t.Fatalf("expandGrokPattern: %v", err)
+	}
+	if want := `(?P<n>[0-9]+)-suffix`; got != want {
+		t.Fatalf("expandGrokPattern = %q, want %q", got, want)
+	}
+}
+
+func TestExpandGrokPatternUnknownReference(t *testing.T) {
+	defs := map[string]string{"ID": `%{MISSING:id}`}
+	if _, err := expandGrokPattern(defs, "ID"); err == nil {
+		t.Fatal("expected an error for an unknown pattern reference")
+	}
+}
+
+func TestExpandGrokPatternCycle(t *testing.T) {
+	defs := map[string]string{
+		"A": `%{B}`,
+		"B": `%{A}`,
+	}
+	if _, err := expandGrokPattern(defs, "A"); err == nil {
+		t.Fatal("expected an error for a reference cycle")
+	}
+}
+
+func TestCompileGrokPatternMissingCapture(t *testing.T) {
+	defs := map[string]string{"TS_ONLY": `%{TIMESTAMP_ISO8601:ts}`}
+	for k, v := range builtinGrokPatterns {
+		defs[k] = v
+	}
+	if _, err := compileGrokPattern(defs, "TS_ONLY"); err == nil {
+		t.Fatal("expected an error when required captures are missing")
+	}
+}
+
+// TestBuiltinGrokPatternsCompile guards against the kind of copy-paste mistake that
+// let ES_SLOWLOG_5X/6X declare the same capture name twice: regexp.Compile accepts
+// duplicate named groups silently, so each bundled pattern is checked for uniqueness
+// explicitly here rather than relying on a compile error to catch it.
+func TestBuiltinGrokPatternsCompile(t *testing.T) {
+	for _, name := range []string{"ES_SLOWLOG_1X", "ES_SLOWLOG_2X", "ES_SLOWLOG_5X", "ES_SLOWLOG_6X", "ES_SLOWLOG_7X", "ES_SLOWLOG_8X"} {
+		re, err := compileGrokPattern(builtinGrokPatterns, name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		seen := make(map[string]bool)
+		for _, n := range re.SubexpNames() {
+			if n == "" {
+				continue
+			}
+			if seen[n] {
+				t.Errorf("%s: capture name %q used more than once", name, n)
+			}
+			seen[n] = true
+		}
+	}
+}
+
+// TestBundledSlowlogPatternsMatchSampleLines guards against the kind of bracket-order
+// mistake that made ES_SLOWLOG_5X/6X silently drop every entry: a pattern that compiles
+// cleanly and has no duplicate capture names can still bind the wrong bracket to
+// log_type. Each bundled pattern is matched here against a realistic line for its ES
+// version and checked for the exact field values a real deployment would produce.
+func TestBundledSlowlogPatternsMatchSampleLines(t *testing.T) {
+	cases := []struct {
+		name string
+		line string
+	}{
+		{
+			name: "ES_SLOWLOG_1X",
+			line: `[2014-01-02 15:04:05,123][WARN][index.search.slowlog.query][nodeA][my-index][0] types[_doc] search_type[QUERY_THEN_FETCH] source[{"query":{"match_all":{}}}], extra_source`,
+		},
+		{
+			name: "ES_SLOWLOG_2X",
+			line: `[2015-01-02 15:04:05,123][WARN][index.search.slowlog.query][nodeA][my-index][0] types[_doc] search_type[QUERY_THEN_FETCH] source[{"query":{"match_all":{}}}], extra_source`,
+		},
+		{
+			name: "ES_SLOWLOG_5X",
+			line: `[2016-01-26T11:21:55,765][WARN ][index.search.slowlog.query] [avI2tUG] [index5][0] took[1.2s], took_millis[1200], types[_doc], stats[], search_type[QUERY_THEN_FETCH], total_shards[1], source[{"query":{"match_all":{}}}], extra_source`,
+		},
+		{
+			name: "ES_SLOWLOG_6X",
+			line: `[2017-01-26T11:21:55,765][WARN ][index.search.slowlog.query] [avI2tUG] [index5][0] took[1.2s], took_millis[1200], types[_doc], stats[], search_type[QUERY_THEN_FETCH], total_shards[1], source[{"query":{"match_all":{}}}], extra_source`,
+		},
+		{
+			name: "ES_SLOWLOG_7X",
+			line: `[2021-01-02T15:04:05,123][index.search.slowlog.query] [nodeA] [my-index][0] took[1.2s], took_millis[1200], types[_doc], stats[], search_type[QUERY_THEN_FETCH], total_shards[1], source[{"query":{"match_all":{}}}], extra_source`,
+		},
+		{
+			name: "ES_SLOWLOG_8X",
+			line: `[2022-01-02T15:04:05,123][index.search.slowlog.query] [nodeA] [my-index][0] took[1.2s], took_millis[1200], types[_doc], stats[], search_type[QUERY_THEN_FETCH], total_shards[1], source[{"query":{"match_all":{}}}], extra_source`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			re, err := compileGrokPattern(builtinGrokPatterns, c.name)
+			if err != nil {
+				t.Fatalf("compileGrokPattern: %v", err)
+			}
+			matches := re.FindStringSubmatch(c.line)
+			if matches == nil {
+				t.Fatalf("pattern did not match sample line: %s", c.line)
+			}
+			fields := make(map[string]string)
+			for i, n := range re.SubexpNames() {
+				if i > 0 && n != "" {
+					fields[n] = matches[i]
+				}
+			}
+			if fields[logTypeField] != "index.search.slowlog.query" {
+				t.Errorf("log_type = %q, want %q", fields[logTypeField], "index.search.slowlog.query")
+			}
+			if fields[indexField] != "my-index" && fields[indexField] != "index5" {
+				t.Errorf("index = %q, want the sample line's index", fields[indexField])
+			}
+			if !strings.Contains(fields[sourceField], "match_all") {
+				t.Errorf("source = %q, want it to contain %q", fields[sourceField], "match_all")
+			}
+		})
+	}
+}
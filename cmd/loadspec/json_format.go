@@ -0,0 +1,86 @@
+package loadspec
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultJSONFieldMap maps each loadspec field parseSlowlogCmd needs to the dotted
+// path it lives at in the ECS-style JSON slowlog ES 7.x+ emits by default. --field-map
+// overrides entries here for clusters that reshape or rename those paths.
+var defaultJSONFieldMap = map[string]string{
+	timestampField:  "@timestamp",
+	logTypeField:    "event.dataset",
+	hostField:       "host.name",
+	indexField:      "elasticsearch.index.name",
+	typesField:      "elasticsearch.slowlog.types",
+	searchTypeField: "elasticsearch.slowlog.search_type",
+	sourceField:     "elasticsearch.slowlog.source",
+}
+
+// parseFieldMap parses a --field-map value of the form
+// "loadspecField=json.path,loadspecField2=json.path2" and merges it over base.
+func parseFieldMap(spec string, base map[string]string) (map[string]string, error) {
+	merged := make(map[string]string, len(base))
+	for k, v := range base {
+		merged[k] = v
+	}
+	if spec == "" {
+		return merged, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("--field-map: malformed entry %q, expected loadspecField=json.path", pair)
+		}
+		merged[kv[0]] = kv[1]
+	}
+	return merged, nil
+}
+
+// looksLikeJSON peeks at a line to decide whether it is a JSON-format slowlog entry
+// rather than the bracketed text format.
+func looksLikeJSON(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "{")
+}
+
+// parseJSONLine decodes a JSON-format slowlog line and populates fields with the
+// loadspec field names fieldMap resolves to, mirroring what the grok regexp path
+// does for the bracketed text format.
+func parseJSONLine(line string, fieldMap map[string]string, fields map[string]string) error {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &doc); err != nil {
+		return fmt.Errorf("parsing JSON slowlog line: %w", err)
+	}
+	for field, path := range fieldMap {
+		v, _ := lookupJSONPath(doc, path)
+		fields[field] = v
+	}
+	return nil
+}
+
+// lookupJSONPath resolves a dot-separated path (e.g. "elasticsearch.index.name")
+// against a decoded JSON document, returning its value as a string.
+func lookupJSONPath(doc map[string]interface{}, path string) (string, bool) {
+	var cur interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		cur, ok = m[part]
+		if !ok {
+			return "", false
+		}
+	}
+	switch v := cur.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,60 @@
+package loadspec
+
+import "testing"
+
+func TestParseFieldMap(t *testing.T) {
+	base := map[string]string{indexField: "elasticsearch.index.name"}
+
+	merged, err := parseFieldMap("", base)
+	if err != nil {
+		t.Fatalf("parseFieldMap: %v", err)
+	}
+	if merged[indexField] != "elasticsearch.index.name" {
+		t.Errorf("parseFieldMap(\"\") did not preserve base entries")
+	}
+
+	merged, err = parseFieldMap("index=custom.index,host=custom.host", base)
+	if err != nil {
+		t.Fatalf("parseFieldMap: %v", err)
+	}
+	if merged[indexField] != "custom.index" {
+		t.Errorf("parseFieldMap override index = %q, want %q", merged[indexField], "custom.index")
+	}
+	if merged[hostField] != "custom.host" {
+		t.Errorf("parseFieldMap override host = %q, want %q", merged[hostField], "custom.host")
+	}
+
+	if _, err := parseFieldMap("malformed", base); err == nil {
+		t.Fatal("expected an error for an entry without '='")
+	}
+	if _, err := parseFieldMap("=custom.host", base); err == nil {
+		t.Fatal("expected an error for an entry with an empty loadspec field")
+	}
+}
+
+func TestLookupJSONPath(t *testing.T) {
+	doc := map[string]interface{}{
+		"elasticsearch": map[string]interface{}{
+			"index": map[string]interface{}{
+				"name": "my-index",
+			},
+			"slowlog": map[string]interface{}{
+				"took_millis": float64(1200),
+			},
+		},
+	}
+
+	v, ok := lookupJSONPath(doc, "elasticsearch.index.name")
+	if !ok || v != "my-index" {
+		t.Errorf("lookupJSONPath(index.name) = (%q, %v), want (%q, true)", v, ok, "my-index")
+	}
+
+	v, ok = lookupJSONPath(doc, "elasticsearch.slowlog.took_millis")
+	if !ok || v != "1200" {
+		t.Errorf("lookupJSONPath(took_millis) = (%q, %v), want (%q, true)", v, ok, "1200")
+	}
+
+	if _, ok := lookupJSONPath(doc, "elasticsearch.missing.path"); ok {
+		t.Error("lookupJSONPath should report false for a missing path")
+	}
+}
@@ -2,10 +2,9 @@ package loadspec
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
+	"math/rand"
 	"os"
-	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -14,14 +13,50 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// These names need to be in sync with the bundled grok patterns in grok.go.
+const (
+	logTypeField    = "log_type"
+	hostField       = "host"
+	timestampField  = "ts"
+	indexField      = "index"
+	typesField      = "types"
+	searchTypeField = "search_type"
+	sourceField     = "source"
+	numFields       = 7
+)
+
 var (
-	indexOverride []string
-	maxDuration   time.Duration
+	indexOverride     []string
+	maxDuration       time.Duration
+	pattern           string
+	patternsFile      string
+	startArg          string
+	endArg            string
+	stream            bool
+	rateMultiplier    float64
+	sampleRate        float64
+	jitter            float64
+	output            string
+	format            string
+	fieldMapArg       string
+	outputHTTPTimeout time.Duration
 )
 
 func init() {
 	parseSlowlogCmd.Flags().StringSliceVar(&indexOverride, "index_override", []string{}, "Override slowlog indexes. It is a list, flag could be repeated if you would the loadtest to hit many indexes.")
 	parseSlowlogCmd.Flags().DurationVar(&maxDuration, "max_duration", time.Duration(0), "Maximum duration of the generated loadspec. It could be smaller, if the slowlog comprise a smaller time frame.")
+	parseSlowlogCmd.Flags().StringVar(&pattern, "pattern", defaultGrokPattern, "Name of a bundled grok-style pattern (e.g. ES_SLOWLOG_7X) or a literal grok expression to match slowlog lines against.")
+	parseSlowlogCmd.Flags().StringVar(&patternsFile, "patterns-file", "", "Path to a file of additional \"NAME pattern\" grok definitions, merged on top of (and able to override) the bundled library.")
+	parseSlowlogCmd.Flags().StringVar(&startArg, "start", "", "Drop entries timestamped before this instant. Accepts RFC3339 (2021-01-02T15:04:05Z) or relative now-style (now-1h).")
+	parseSlowlogCmd.Flags().StringVar(&endArg, "end", "", "Drop entries timestamped after this instant. Accepts RFC3339 (2021-01-02T15:04:05Z) or relative now-style (now-1h).")
+	parseSlowlogCmd.Flags().BoolVar(&stream, "stream", false, "Skip the global sort and emit each entry to --output as soon as it is parsed, computing delay from the running previous timestamp. Lets parseslowlog be fed from tail -F on a live slowlog.")
+	parseSlowlogCmd.Flags().Float64Var(&rateMultiplier, "rate_multiplier", 1, "Scale all delays by 1/rate_multiplier. 2 doubles the load, 0.5 halves it.")
+	parseSlowlogCmd.Flags().Float64Var(&sampleRate, "sample_rate", 1, "Probability of keeping each entry, e.g. 0.1 keeps roughly 10% of them. The wall-clock timeline of the entries that remain is preserved.")
+	parseSlowlogCmd.Flags().Float64Var(&jitter, "jitter", 0, "Randomize each delay uniformly within +/- this fraction, e.g. 0.1 for +/-10%.")
+	parseSlowlogCmd.Flags().StringVar(&output, "output", "", "Where to write the generated entries. Defaults to stdout. Also accepts file://path?rotate=100MB, kafka://broker/topic, or http(s):// to POST each entry to an esperf gen worker.")
+	parseSlowlogCmd.Flags().StringVar(&format, "format", "auto", "Slowlog format: \"auto\" (peek each line for a leading '{'), \"json\" (one ECS-style JSON object per line, as emitted since ES 7.x), or \"text\" (the bracketed format matched by --pattern).")
+	parseSlowlogCmd.Flags().StringVar(&fieldMapArg, "field-map", "", "Comma-separated loadspecField=json.path overrides for --format=json, e.g. \"index=elasticsearch.index.name\".")
+	parseSlowlogCmd.Flags().DurationVar(&outputHTTPTimeout, "output-http-timeout", defaultHTTPSinkTimeout, "Timeout for each entry POSTed by an http(s):// --output sink.")
 }
 
 var parseSlowlogCmd = &cobra.Command{
@@ -29,18 +64,6 @@ var parseSlowlogCmd = &cobra.Command{
 	Short: "Outputs a replayable loadspec based on the passed-in slowlog and parameters.",
 	Long:  "Outputs a replayable loadspec based on the passed-in slowlog and parameters.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// These constants need to be in sync with the regular expression bellow.
-		const (
-			logTypeField    = "log_type"
-			hostField       = "host"
-			timestampField  = "ts"
-			indexField      = "index"
-			typesField      = "types"
-			searchTypeField = "search_type"
-			sourceField     = "source"
-			numFields       = 6
-		)
-
 		var urlArg string
 		if len(args) > 0 {
 			// To keep in par with gen, we only consider the host or host:port part of the URL.
@@ -64,23 +87,88 @@ var parseSlowlogCmd = &cobra.Command{
 		// Regular expression setup.
 		// The solution is based on regexp's named matches. For each entry, we build a map of
 		// of fields and values. This map is encoded as json and (buffered) written to stdout.
-		re, err := regexp.Compile(`\[(?P<ts>[^]]+)\].?\[.*\].?\[(?P<log_type>[^]]+)\].?\[(?P<host>[^]]+)\].?\[(?P<index>[^]]+)\].?\[.*\].*types\[(?P<types>[^]]+)\].*search_type\[(?P<search_type>[^]]+)\].*source\[(?P<source>.*)\], extra_source`)
+		// The regexp itself comes from a grok-style pattern, either one of the bundled
+		// per-ES-version definitions or a literal expression passed via --pattern, optionally
+		// layered with custom fragments from --patterns-file.
+		defs := make(map[string]string, len(builtinGrokPatterns))
+		for k, v := range builtinGrokPatterns {
+			defs[k] = v
+		}
+		if patternsFile != "" {
+			if err := loadPatternsFile(patternsFile, defs); err != nil {
+				return err
+			}
+		}
+		re, err := compileGrokPattern(defs, pattern)
 		if err != nil {
 			return err
 		}
 		subExpNames := re.SubexpNames()
 
+		switch format {
+		case "auto", "json", "text":
+		default:
+			return fmt.Errorf("--format: unknown value %q, want auto, json or text", format)
+		}
+		fieldMap, err := parseFieldMap(fieldMapArg, defaultJSONFieldMap)
+		if err != nil {
+			return err
+		}
+
+		// Time window setup. now is resolved once so that a "now-"-relative --start and
+		// --end refer to the same instant instead of drifting apart while we scan stdin.
+		now := time.Now()
+		var start, end time.Time
+		if startArg != "" {
+			if start, err = parseTimeArg(startArg, now); err != nil {
+				return fmt.Errorf("--start: %w", err)
+			}
+		}
+		if endArg != "" {
+			if end, err = parseTimeArg(endArg, now); err != nil {
+				return fmt.Errorf("--end: %w", err)
+			}
+		}
+
+		rng := rand.New(rand.NewSource(now.UnixNano()))
+
+		// Output sink. In --stream mode each entry is flushed as soon as it is parsed (when
+		// the sink supports it); otherwise it is only written after the final sort.
+		sink, err := newSink(output, outputHTTPTimeout)
+		if err != nil {
+			return err
+		}
+		defer sink.Close()
+
 		var entries loadspec.ByDelaySinceLastNanos
 		fields := make(map[string]string, numFields)
 		scanner := bufio.NewScanner(os.Stdin)
 		count := 0
+		var elapsed, previousTimestamp int64
 		for scanner.Scan() {
-			// Building a map using named matches.
-			matches := re.FindAllStringSubmatch(scanner.Text(), -1)[0]
-			for i, n := range matches {
-				// Removing the first match, which is the whole line.
-				if i > 0 {
-					fields[subExpNames[i]] = n
+			line := scanner.Text()
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			useJSON := format == "json" || (format == "auto" && looksLikeJSON(line))
+			if useJSON {
+				if err := parseJSONLine(line, fieldMap, fields); err != nil {
+					return err
+				}
+			} else {
+				// Building a map using named matches. Real captures routinely contain lines
+				// the active pattern doesn't cover (log-rotation banners, GC noise, a stray
+				// format from another ES version); skip those rather than erroring the whole
+				// run.
+				matches := re.FindStringSubmatch(line)
+				if matches == nil {
+					continue
+				}
+				for i, n := range matches {
+					// Removing the first match, which is the whole line.
+					if i > 0 {
+						fields[subExpNames[i]] = n
+					}
 				}
 			}
 			// For now, only processing queries.
@@ -88,13 +176,28 @@ var parseSlowlogCmd = &cobra.Command{
 				continue
 			}
 
-			entry := loadspec.Entry{Source: fields[sourceField]}
-			// Making timestamp relative to the previous one. Simulate inter-arrival time can be as easy
-			// as a time.Sleep and trigger a goroutine.
-			t, err := time.Parse(timeLayout, strings.Replace(fields[timestampField], ",", ".", 1))
+			t, err := parseSlowlogTimestamp(fields[timestampField])
 			if err != nil {
 				return err
 			}
+			// Entries outside the requested --start/--end window are dropped here, before
+			// sorting and before delay-since-last is computed, so the replay cleanly covers
+			// just the window the user asked for.
+			if !start.IsZero() && t.Before(start) {
+				continue
+			}
+			if !end.IsZero() && t.After(end) {
+				continue
+			}
+			// --sample_rate drops entries probabilistically before delay is computed, so the
+			// wall-clock gaps between the entries that remain stay true to the original.
+			if !keepSampled(rng, sampleRate) {
+				continue
+			}
+
+			entry := loadspec.Entry{Source: fields[sourceField]}
+			// Making timestamp relative to the previous one. Simulate inter-arrival time can be as easy
+			// as a time.Sleep and trigger a goroutine.
 			// Keeping timestamp here for post-processing bellow.
 			entry.DelaySinceLastNanos = t.UnixNano()
 			// Host argument is treated as full URL. This keeps consistency between here and gen command.
@@ -117,22 +220,48 @@ var parseSlowlogCmd = &cobra.Command{
 				st = fmt.Sprintf("?search_type=%s", strings.ToLower(fields[searchTypeField]))
 			}
 			entry.URL = fmt.Sprintf("%s%s", strings.Join(path, "/"), st)
+
+			if stream {
+				// No global sort in streaming mode: delay is computed against whatever the
+				// previous line happened to be, and the entry is flushed right away so a
+				// tail -F on a live slowlog can be replayed in near real time.
+				entry.ID = count
+				currTimestamp := entry.DelaySinceLastNanos
+				entry.DelaySinceLastNanos = streamDelayNanos(currTimestamp, previousTimestamp, count == 0)
+				previousTimestamp = currTimestamp
+				entry.DelaySinceLastNanos = jitterDelay(rng, scaleDelay(entry.DelaySinceLastNanos, rateMultiplier), jitter)
+				if err := sink.Write(&entry); err != nil {
+					return err
+				}
+				if f, ok := sink.(flusher); ok {
+					if err := f.Flush(); err != nil {
+						return err
+					}
+				}
+				elapsed += entry.DelaySinceLastNanos
+				count++
+				if maxDuration.Nanoseconds() > 0 && elapsed >= maxDuration.Nanoseconds() {
+					break
+				}
+				continue
+			}
+
 			entries = append(entries, &entry)
 			count++
 		}
 		if err := scanner.Err(); err != nil {
 			return err
 		}
+		if stream {
+			fmt.Fprintf(os.Stderr, "Test duration: %v\n", time.Duration(elapsed))
+			return nil
+		}
 		// Slow log entries are not guaranteed to be timestamp ordered.
 		sort.Sort(entries)
 
-		// Writer and encoding configuration.
-		writer := bufio.NewWriter(os.Stdout)
-		defer writer.Flush()
-		enc := json.NewEncoder(writer)
-		var elapsed, previousTimestamp, currTimestamp int64
+		var currTimestamp int64
 		for i, e := range entries {
-            e.ID = i
+			e.ID = i
 			// Adjusting from timestamp to delay since last request. That makes a lot easier to replay.
 			currTimestamp = e.DelaySinceLastNanos
 			if i == 0 {
@@ -141,7 +270,8 @@ var parseSlowlogCmd = &cobra.Command{
 				e.DelaySinceLastNanos -= previousTimestamp
 			}
 			previousTimestamp = currTimestamp
-			if err := enc.Encode(&e); err != nil {
+			e.DelaySinceLastNanos = jitterDelay(rng, scaleDelay(e.DelaySinceLastNanos, rateMultiplier), jitter)
+			if err := sink.Write(e); err != nil {
 				return err
 			}
 			elapsed += e.DelaySinceLastNanos
@@ -0,0 +1,35 @@
+package loadspec
+
+import "math/rand"
+
+// keepSampled reports whether an entry survives --sample_rate, e.g. a rate of 0.1 keeps
+// roughly 10% of entries. A rate of 1 (the default) always keeps.
+func keepSampled(rng *rand.Rand, sampleRate float64) bool {
+	if sampleRate >= 1 {
+		return true
+	}
+	return rng.Float64() < sampleRate
+}
+
+// scaleDelay applies --rate_multiplier to a delay. A multiplier of 2 halves the delay
+// (double the load), while a fractional multiplier below 1 stretches it out.
+func scaleDelay(delay int64, rateMultiplier float64) int64 {
+	if rateMultiplier == 1 || rateMultiplier <= 0 {
+		return delay
+	}
+	return int64(float64(delay) / rateMultiplier)
+}
+
+// jitterDelay perturbs a delay uniformly within +/- jitter (e.g. 0.1 for +/-10%),
+// clamped so it never goes negative.
+func jitterDelay(rng *rand.Rand, delay int64, jitter float64) int64 {
+	if jitter <= 0 {
+		return delay
+	}
+	factor := 1 + (rng.Float64()*2-1)*jitter
+	jittered := int64(float64(delay) * factor)
+	if jittered < 0 {
+		return 0
+	}
+	return jittered
+}
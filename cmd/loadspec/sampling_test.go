@@ -0,0 +1,66 @@
+package loadspec
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestKeepSampled(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	if !keepSampled(rng, 1) {
+		t.Error("keepSampled(1) should always keep")
+	}
+	if !keepSampled(rng, 2) {
+		t.Error("keepSampled(>1) should always keep")
+	}
+
+	kept := 0
+	const n = 10000
+	for i := 0; i < n; i++ {
+		if keepSampled(rng, 0.25) {
+			kept++
+		}
+	}
+	if kept < n/8 || kept > n/2 {
+		t.Errorf("keepSampled(0.25) kept %d/%d, want roughly 25%%", kept, n)
+	}
+}
+
+func TestScaleDelay(t *testing.T) {
+	if got := scaleDelay(1000, 1); got != 1000 {
+		t.Errorf("scaleDelay(1000, 1) = %d, want 1000", got)
+	}
+	if got := scaleDelay(1000, 2); got != 500 {
+		t.Errorf("scaleDelay(1000, 2) = %d, want 500", got)
+	}
+	if got := scaleDelay(1000, 0.5); got != 2000 {
+		t.Errorf("scaleDelay(1000, 0.5) = %d, want 2000", got)
+	}
+	if got := scaleDelay(1000, 0); got != 1000 {
+		t.Errorf("scaleDelay(1000, 0) = %d, want delay unchanged", got)
+	}
+	if got := scaleDelay(1000, -1); got != 1000 {
+		t.Errorf("scaleDelay(1000, -1) = %d, want delay unchanged", got)
+	}
+}
+
+func TestJitterDelay(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	if got := jitterDelay(rng, 1000, 0); got != 1000 {
+		t.Errorf("jitterDelay(1000, 0) = %d, want 1000 unchanged", got)
+	}
+
+	for i := 0; i < 1000; i++ {
+		got := jitterDelay(rng, 1000, 0.1)
+		if got < 0 {
+			t.Fatalf("jitterDelay returned a negative delay: %d", got)
+		}
+		if got < 900 || got > 1100 {
+			t.Errorf("jitterDelay(1000, 0.1) = %d, want within +/-10%%", got)
+		}
+	}
+
+	if got := jitterDelay(rng, 10, 100); got < 0 {
+		t.Errorf("jitterDelay must clamp negative results, got %d", got)
+	}
+}
@@ -0,0 +1,244 @@
+package loadspec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/danielfireman/esperf/loadspec"
+)
+
+// defaultHTTPSinkTimeout bounds how long a single entry POST to an http(s):// sink may
+// take, so a slow or wedged esperf gen worker can't hang parseslowlog forever.
+const defaultHTTPSinkTimeout = 10 * time.Second
+
+// Sink is the output-plugin interface the emission stage of parseslowlog writes
+// through, selected via --output. This mirrors the way esperf already treats its
+// inputs as pluggable, so a large capture can stream straight into wherever it needs
+// to go instead of always round-tripping through stdout and a shell redirect.
+type Sink interface {
+	Write(e *loadspec.Entry) error
+	Close() error
+}
+
+// flusher is implemented by sinks that can push buffered entries out on demand, used
+// by parseslowlog's --stream mode to make each entry visible as soon as it is parsed.
+type flusher interface {
+	Flush() error
+}
+
+// newSink builds a Sink from --output. An empty value keeps today's behavior of
+// writing newline-delimited JSON to stdout. Recognized schemes otherwise:
+// file://path?rotate=100MB, kafka://broker/topic, and http(s):// to POST each entry
+// to an esperf gen worker.
+func newSink(output string, httpTimeout time.Duration) (Sink, error) {
+	if output == "" {
+		return newStdoutSink(), nil
+	}
+	u, err := url.Parse(output)
+	if err != nil {
+		return nil, fmt.Errorf("--output %q: %w", output, err)
+	}
+	switch u.Scheme {
+	case "file":
+		return newFileSink(u)
+	case "kafka":
+		return newKafkaSink(u)
+	case "http", "https":
+		return newHTTPSink(u, httpTimeout), nil
+	default:
+		return nil, fmt.Errorf("--output %q: unsupported scheme %q", output, u.Scheme)
+	}
+}
+
+// stdoutSink is today's behavior: buffered newline-delimited JSON on stdout.
+type stdoutSink struct {
+	writer *bufio.Writer
+	enc    *json.Encoder
+}
+
+func newStdoutSink() *stdoutSink {
+	w := bufio.NewWriter(os.Stdout)
+	return &stdoutSink{writer: w, enc: json.NewEncoder(w)}
+}
+
+func (s *stdoutSink) Write(e *loadspec.Entry) error { return s.enc.Encode(e) }
+func (s *stdoutSink) Flush() error                  { return s.writer.Flush() }
+func (s *stdoutSink) Close() error                  { return s.writer.Flush() }
+
+// fileSink writes newline-delimited JSON to disk, rotating to path.N once the
+// current file would grow past rotateSize (0 means never rotate).
+type fileSink struct {
+	path       string
+	rotateSize int64
+
+	f       *os.File
+	writer  *bufio.Writer
+	part    int
+	written int64
+}
+
+func newFileSink(u *url.URL) (*fileSink, error) {
+	rotateSize, err := parseByteSize(u.Query().Get("rotate"))
+	if err != nil {
+		return nil, fmt.Errorf("--output file rotate param: %w", err)
+	}
+	s := &fileSink{path: u.Host + u.Path, rotateSize: rotateSize}
+	if err := s.openCurrent(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSink) currentPath() string {
+	if s.part == 0 {
+		return s.path
+	}
+	return fmt.Sprintf("%s.%d", s.path, s.part)
+}
+
+func (s *fileSink) openCurrent() error {
+	f, err := os.Create(s.currentPath())
+	if err != nil {
+		return err
+	}
+	s.f = f
+	s.writer = bufio.NewWriter(f)
+	s.written = 0
+	return nil
+}
+
+func (s *fileSink) Write(e *loadspec.Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if s.rotateSize > 0 && s.written > 0 && s.written+int64(len(b)) > s.rotateSize {
+		if err := s.writer.Flush(); err != nil {
+			return err
+		}
+		if err := s.f.Close(); err != nil {
+			return err
+		}
+		s.part++
+		if err := s.openCurrent(); err != nil {
+			return err
+		}
+	}
+	n, err := s.writer.Write(b)
+	s.written += int64(n)
+	return err
+}
+
+func (s *fileSink) Flush() error { return s.writer.Flush() }
+
+func (s *fileSink) Close() error {
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	return s.f.Close()
+}
+
+// parseByteSize parses sizes like "100MB", "1GB", "512KB" or a bare byte count.
+// An empty string means no limit.
+func parseByteSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(upper, u.suffix) {
+			n, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(upper, u.suffix)), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid size %q", s)
+			}
+			return n * u.mult, nil
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n, nil
+}
+
+// httpSink POSTs each entry as a JSON body, e.g. directly to an esperf gen worker for
+// injection without an intermediate file.
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+func newHTTPSink(u *url.URL, timeout time.Duration) *httpSink {
+	return &httpSink{url: u.String(), client: &http.Client{Timeout: timeout}}
+}
+
+func (s *httpSink) Write(e *loadspec.Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("output POST to %s: unexpected status %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+func (s *httpSink) Close() error { return nil }
+
+// kafkaSink publishes each entry as a JSON message on a Kafka topic.
+type kafkaSink struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+func newKafkaSink(u *url.URL) (*kafkaSink, error) {
+	topic := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || topic == "" {
+		return nil, fmt.Errorf("--output kafka URL must look like kafka://broker/topic")
+	}
+	cfg := sarama.NewConfig()
+	cfg.Producer.Return.Successes = true
+	producer, err := sarama.NewSyncProducer([]string{u.Host}, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to kafka broker %s: %w", u.Host, err)
+	}
+	return &kafkaSink{producer: producer, topic: topic}, nil
+}
+
+func (s *kafkaSink) Write(e *loadspec.Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, _, err = s.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: s.topic,
+		Value: sarama.ByteEncoder(b),
+	})
+	return err
+}
+
+func (s *kafkaSink) Close() error { return s.producer.Close() }
@@ -0,0 +1,112 @@
+package loadspec
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/danielfireman/esperf/loadspec"
+)
+
+func TestParseByteSize(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"512", 512, false},
+		{"512B", 512, false},
+		{"100MB", 100 << 20, false},
+		{"1GB", 1 << 30, false},
+		{"4KB", 4 << 10, false},
+		{"not-a-size", 0, true},
+	}
+	for _, c := range cases {
+		got, err := parseByteSize(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("parseByteSize(%q): expected an error", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseByteSize(%q): %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parseByteSize(%q) = %d, want %d", c.in, got, c.want)
+		}
+	}
+}
+
+func TestNewSinkUnsupportedScheme(t *testing.T) {
+	if _, err := newSink("ftp://example.com/path", defaultHTTPSinkTimeout); err == nil {
+		t.Fatal("expected an error for an unsupported --output scheme")
+	}
+}
+
+// TestFileSinkRotation writes enough entries to cross a tiny rotate threshold and
+// checks that each rotated part file holds the record(s) it was expected to.
+func TestFileSinkRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.json")
+	u, err := url.Parse("file://" + path + "?rotate=1B")
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	s, err := newFileSink(u)
+	if err != nil {
+		t.Fatalf("newFileSink: %v", err)
+	}
+
+	entries := []*loadspec.Entry{
+		{ID: 0, URL: "http://a/_search", Source: `{"q":0}`},
+		{ID: 1, URL: "http://a/_search", Source: `{"q":1}`},
+		{ID: 2, URL: "http://a/_search", Source: `{"q":2}`},
+	}
+	for _, e := range entries {
+		if err := s.Write(e); err != nil {
+			t.Fatalf("Write(%d): %v", e.ID, err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	for i, want := range entries {
+		part := path
+		if i > 0 {
+			part = fmt.Sprintf("%s.%d", path, i)
+		}
+		got := readSingleEntry(t, part)
+		if got.ID != want.ID || got.Source != want.Source {
+			t.Errorf("%s = %+v, want %+v", part, got, want)
+		}
+	}
+}
+
+func readSingleEntry(t *testing.T, path string) loadspec.Entry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening %s: %v", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		t.Fatalf("%s: expected one line, got none", path)
+	}
+	var e loadspec.Entry
+	if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+		t.Fatalf("%s: unmarshal: %v", path, err)
+	}
+	if scanner.Scan() {
+		t.Fatalf("%s: expected exactly one line", path)
+	}
+	return e
+}
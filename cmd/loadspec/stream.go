@@ -0,0 +1,17 @@
+package loadspec
+
+// streamDelayNanos computes an entry's delay-since-last for --stream mode, where there
+// is no global sort to fall back on. first is true for the very first entry, which has
+// no predecessor to measure against. Without a global sort, an out-of-order input
+// (plausible when tailing a live or merged source) can make currTimestamp-previous
+// negative; clamp it rather than handing the sink a negative delay.
+func streamDelayNanos(currTimestamp, previousTimestamp int64, first bool) int64 {
+	if first {
+		return 0
+	}
+	delay := currTimestamp - previousTimestamp
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
@@ -0,0 +1,15 @@
+package loadspec
+
+import "testing"
+
+func TestStreamDelayNanos(t *testing.T) {
+	if got := streamDelayNanos(1000, 0, true); got != 0 {
+		t.Errorf("streamDelayNanos(first) = %d, want 0", got)
+	}
+	if got := streamDelayNanos(1500, 1000, false); got != 500 {
+		t.Errorf("streamDelayNanos(1500, 1000) = %d, want 500", got)
+	}
+	if got := streamDelayNanos(1000, 1500, false); got != 0 {
+		t.Errorf("streamDelayNanos(out-of-order) = %d, want 0 (clamped)", got)
+	}
+}
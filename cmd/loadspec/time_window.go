@@ -0,0 +1,32 @@
+package loadspec
+
+import (
+	"strings"
+	"time"
+)
+
+// parseSlowlogTimestamp parses a slowlog entry's timestamp field, which is either the
+// bracketed format's timeLayout (with a ',' millisecond separator) or the RFC3339Nano
+// timestamp an ECS-style JSON slowlog line carries in @timestamp.
+func parseSlowlogTimestamp(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(timeLayout, strings.Replace(s, ",", ".", 1))
+}
+
+// parseTimeArg parses a --start/--end value. It accepts a standard RFC3339 timestamp,
+// or a relative "now"-style offset such as "now-1h" or "now+30m", resolved against now.
+func parseTimeArg(arg string, now time.Time) (time.Time, error) {
+	if rest := strings.TrimPrefix(arg, "now"); rest != arg {
+		if rest == "" {
+			return now, nil
+		}
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(d), nil
+	}
+	return time.Parse(time.RFC3339, arg)
+}
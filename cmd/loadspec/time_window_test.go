@@ -0,0 +1,57 @@
+package loadspec
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeArg(t *testing.T) {
+	now := time.Date(2026, 7, 25, 12, 0, 0, 0, time.UTC)
+
+	got, err := parseTimeArg("now-1h", now)
+	if err != nil {
+		t.Fatalf("parseTimeArg: %v", err)
+	}
+	if want := now.Add(-time.Hour); !got.Equal(want) {
+		t.Errorf("parseTimeArg(now-1h) = %v, want %v", got, want)
+	}
+
+	got, err = parseTimeArg("now", now)
+	if err != nil {
+		t.Fatalf("parseTimeArg: %v", err)
+	}
+	if !got.Equal(now) {
+		t.Errorf("parseTimeArg(now) = %v, want %v", got, now)
+	}
+
+	got, err = parseTimeArg("2021-01-02T15:04:05Z", now)
+	if err != nil {
+		t.Fatalf("parseTimeArg: %v", err)
+	}
+	want, _ := time.Parse(time.RFC3339, "2021-01-02T15:04:05Z")
+	if !got.Equal(want) {
+		t.Errorf("parseTimeArg(RFC3339) = %v, want %v", got, want)
+	}
+
+	if _, err := parseTimeArg("not-a-time", now); err == nil {
+		t.Fatal("expected an error for an unparseable value")
+	}
+}
+
+func TestParseSlowlogTimestamp(t *testing.T) {
+	got, err := parseSlowlogTimestamp("2021-01-02 15:04:05,123")
+	if err != nil {
+		t.Fatalf("parseSlowlogTimestamp (text format): %v", err)
+	}
+	if got.Nanosecond()/1e6 != 123 {
+		t.Errorf("parseSlowlogTimestamp millis = %d, want 123", got.Nanosecond()/1e6)
+	}
+
+	got, err = parseSlowlogTimestamp("2021-01-02T15:04:05.123Z")
+	if err != nil {
+		t.Fatalf("parseSlowlogTimestamp (RFC3339Nano): %v", err)
+	}
+	if got.Nanosecond()/1e6 != 123 {
+		t.Errorf("parseSlowlogTimestamp millis = %d, want 123", got.Nanosecond()/1e6)
+	}
+}